@@ -0,0 +1,71 @@
+package options
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// Cookie contains configuration options for the cookie that the proxy sets
+// to maintain a user's session, including its scoping, lifetime and the
+// security attributes applied to it.
+type Cookie struct {
+	Name     string        `flag:"cookie-name" cfg:"cookie_name"`
+	Secret   string        `flag:"cookie-secret" cfg:"cookie_secret"`
+	Domains  []string      `flag:"cookie-domain" cfg:"cookie_domains"`
+	Path     string        `flag:"cookie-path" cfg:"cookie_path"`
+	Expire   time.Duration `flag:"cookie-expire" cfg:"cookie_expire"`
+	Refresh  time.Duration `flag:"cookie-refresh" cfg:"cookie_refresh"`
+	Secure   bool          `flag:"cookie-secure" cfg:"cookie_secure"`
+	HTTPOnly bool          `flag:"cookie-httponly" cfg:"cookie_httponly"`
+	SameSite string        `flag:"cookie-samesite" cfg:"cookie_samesite"`
+
+	// MaxChunkSize overrides the default 4000 byte threshold
+	// cookies.SetChunked uses to decide when to split a value across
+	// multiple "name_0", "name_1", ... cookies. Zero keeps the package
+	// default.
+	MaxChunkSize int `flag:"cookie-max-chunk-size" cfg:"cookie_max_chunk_size"`
+
+	// HostOnly, when set, forces the cookie's Domain attribute to be
+	// omitted so the browser scopes it strictly to the exact request host
+	// (a "host-only" cookie) instead of whatever Domains would otherwise
+	// select. It takes precedence over Domains.
+	HostOnly bool `flag:"cookie-host-only" cfg:"cookie_host_only"`
+
+	// Partitioned selects whether the CHIPS "Partitioned" attribute is
+	// added, scoping the cookie to the top-level site for
+	// third-party-embedded use. cookies.WriteCookie applies it when this
+	// value is passed as its partitioned argument; MakeCookieFromOptions and
+	// SetChunked do not read it. It only has an effect on a
+	// "SameSite=None; Secure" cookie.
+	Partitioned bool `flag:"cookie-partitioned" cfg:"cookie_partitioned"`
+
+	// DisableSameSiteQuirks turns off the registry of per-User-Agent
+	// SameSite workarounds (see pkg/cookies/samesite_quirks.go), for
+	// deployments that consider UA sniffing itself a privacy concern and
+	// would rather send the configured SameSite attribute unmodified to
+	// every client.
+	DisableSameSiteQuirks bool `flag:"cookie-disable-samesite-quirks" cfg:"cookie_disable_samesite_quirks"`
+}
+
+// NewLegacyCookieFlagSet creates a pflag.FlagSet for the legacy cookie
+// options, with the same defaults oauth2-proxy has always shipped.
+func NewLegacyCookieFlagSet() *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet("cookie", pflag.ExitOnError)
+
+	flagSet.String("cookie-name", "_oauth2_proxy", "the name of the cookie that the oauth_proxy creates")
+	flagSet.String("cookie-secret", "", "the seed string for secure cookies (optionally base64 encoded)")
+	flagSet.StringSlice("cookie-domain", []string{}, "Optional cookie domains to force cookies to (ie: `.yourcompany.com`). The longest domain matching the request's host will be used (or the shortest cookie domain if there is no match).")
+	flagSet.String("cookie-path", "/", "an optional cookie path to force cookies to (ie: /poc/)")
+	flagSet.Duration("cookie-expire", time.Duration(168)*time.Hour, "expire timeframe for cookie")
+	flagSet.Duration("cookie-refresh", time.Duration(0), "refresh the cookie after this duration; 0 to disable")
+	flagSet.Bool("cookie-secure", true, "set secure (HTTPS) cookie flag")
+	flagSet.Bool("cookie-httponly", true, "set HttpOnly cookie flag")
+	flagSet.String("cookie-samesite", "", "set SameSite cookie attribute (ie: \"lax\", \"strict\", \"none\", \"disabled\", or \"\")")
+	flagSet.Int("cookie-max-chunk-size", 0, "override the default 4000 byte threshold for splitting large cookie values across multiple chunks; 0 uses the package default")
+	flagSet.Bool("cookie-host-only", false, "omit the cookie Domain attribute entirely so the browser scopes the cookie to the exact request host")
+	flagSet.Bool("cookie-partitioned", false, "add the CHIPS Partitioned attribute to cookies (only takes effect on a SameSite=None; Secure cookie)")
+	flagSet.Bool("cookie-disable-samesite-quirks", false, "disable User-Agent sniffing for known SameSite=None quirks (e.g. legacy Safari); send the configured SameSite attribute unmodified to every client")
+
+	return flagSet
+}
@@ -0,0 +1,91 @@
+package cookies
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/oauth2-proxy/oauth2-proxy/pkg/apis/options"
+)
+
+// defaultMaxChunkSize is the largest value, in bytes, SetChunked will pack
+// into a single cookie before spilling the remainder into another one.
+// Browsers commonly cap individual cookies at 4096 bytes; 4000 leaves room
+// for the name and attributes.
+const defaultMaxChunkSize = 4000
+
+// chunkedCookieName returns the name of the i'th chunk of a chunked cookie.
+func chunkedCookieName(name string, i int) string {
+	return fmt.Sprintf("%s_%d", name, i)
+}
+
+// SetChunked writes value split across as many "name_0", "name_1", ...
+// cookies as needed to keep each individual cookie's value to at most
+// cookieOpts.MaxChunkSize bytes (defaultMaxChunkSize if unset), and clears any chunks left over from
+// a previous, longer value. This lets session stores hold payloads (e.g.
+// OIDC ID tokens) that exceed the browser's per-cookie size limit without
+// each store reimplementing the splitting logic.
+func SetChunked(w http.ResponseWriter, req *http.Request, name string, value string, cookieOpts *options.Cookie, expiration time.Duration, now time.Time) {
+	previousChunks := CountChunks(req, name)
+
+	maxChunkSize := cookieOpts.MaxChunkSize
+	if maxChunkSize <= 0 {
+		maxChunkSize = defaultMaxChunkSize
+	}
+
+	var chunks []string
+	for len(value) > 0 {
+		end := maxChunkSize
+		if end > len(value) {
+			end = len(value)
+		}
+		chunks = append(chunks, value[:end])
+		value = value[end:]
+	}
+
+	for i, chunk := range chunks {
+		http.SetCookie(w, MakeCookieFromOptions(req, chunkedCookieName(name, i), chunk, cookieOpts, expiration, now))
+	}
+
+	// The new value packed into fewer chunks than the one it replaces;
+	// delete the now-orphaned tail so it doesn't linger forever.
+	for i := len(chunks); i < previousChunks; i++ {
+		expired := MakeCookieFromOptions(req, chunkedCookieName(name, i), "", cookieOpts, time.Hour*-1, now)
+		http.SetCookie(w, expired)
+	}
+}
+
+// GetChunked reconciles a value previously written with SetChunked back into
+// a single string by reading "name_0", "name_1", ... in order until a chunk
+// is missing.
+func GetChunked(req *http.Request, name string) (string, error) {
+	var b strings.Builder
+	count := 0
+	for {
+		c, err := req.Cookie(chunkedCookieName(name, count))
+		if err != nil {
+			break
+		}
+		b.WriteString(c.Value)
+		count++
+	}
+	if count == 0 {
+		return "", http.ErrNoCookie
+	}
+	return b.String(), nil
+}
+
+// CountChunks returns the number of chunks a chunked cookie named name was
+// last written with, so callers (e.g. logout handlers) can detect and clear
+// stale chunks.
+func CountChunks(req *http.Request, name string) int {
+	count := 0
+	for {
+		if _, err := req.Cookie(chunkedCookieName(name, count)); err != nil {
+			break
+		}
+		count++
+	}
+	return count
+}
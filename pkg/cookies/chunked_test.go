@@ -0,0 +1,83 @@
+package cookies
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oauth2-proxy/oauth2-proxy/pkg/apis/options"
+)
+
+func applyChunkedCookies(rec *httptest.ResponseRecorder) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range rec.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	return req
+}
+
+func TestSetChunkedGetChunkedRoundTrip(t *testing.T) {
+	cookieOpts := &options.Cookie{Path: "/", MaxChunkSize: 10}
+	value := strings.Repeat("a", 25)
+
+	rec := httptest.NewRecorder()
+	SetChunked(rec, httptest.NewRequest(http.MethodGet, "/", nil), "sess", value, cookieOpts, time.Hour, time.Unix(0, 0))
+
+	req := applyChunkedCookies(rec)
+	got, err := GetChunked(req, "sess")
+	if err != nil {
+		t.Fatalf("GetChunked returned error: %v", err)
+	}
+	if got != value {
+		t.Errorf("GetChunked = %q, want %q", got, value)
+	}
+	if n := CountChunks(req, "sess"); n != 3 {
+		t.Errorf("CountChunks = %d, want 3", n)
+	}
+}
+
+func TestSetChunkedDefaultMaxChunkSize(t *testing.T) {
+	cookieOpts := &options.Cookie{Path: "/"}
+	value := strings.Repeat("b", defaultMaxChunkSize+1)
+
+	rec := httptest.NewRecorder()
+	SetChunked(rec, httptest.NewRequest(http.MethodGet, "/", nil), "sess", value, cookieOpts, time.Hour, time.Unix(0, 0))
+
+	req := applyChunkedCookies(rec)
+	if n := CountChunks(req, "sess"); n != 2 {
+		t.Errorf("CountChunks = %d, want 2 when MaxChunkSize is unset", n)
+	}
+}
+
+func TestSetChunkedClearsOrphanedTailChunks(t *testing.T) {
+	cookieOpts := &options.Cookie{Path: "/", MaxChunkSize: 10}
+
+	rec := httptest.NewRecorder()
+	SetChunked(rec, httptest.NewRequest(http.MethodGet, "/", nil), "sess", strings.Repeat("a", 25), cookieOpts, time.Hour, time.Unix(0, 0))
+	req := applyChunkedCookies(rec)
+	if n := CountChunks(req, "sess"); n != 3 {
+		t.Fatalf("setup: CountChunks = %d, want 3", n)
+	}
+
+	rec2 := httptest.NewRecorder()
+	SetChunked(rec2, req, "sess", "short", cookieOpts, time.Hour, time.Unix(0, 1))
+
+	expiredChunk2 := false
+	for _, c := range rec2.Result().Cookies() {
+		if c.Name == "sess_2" && c.Expires.Before(time.Unix(0, 1)) {
+			expiredChunk2 = true
+		}
+	}
+	if !expiredChunk2 {
+		t.Error("expected the now-unused sess_2 chunk to be expired")
+	}
+}
+
+func TestGetChunkedNoCookiesReturnsErrNoCookie(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if _, err := GetChunked(req, "sess"); err != http.ErrNoCookie {
+		t.Errorf("GetChunked error = %v, want http.ErrNoCookie", err)
+	}
+}
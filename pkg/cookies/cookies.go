@@ -7,16 +7,21 @@ import (
 	"strings"
 	"time"
 
-	"github.com/avct/uasurfer"
 	"github.com/oauth2-proxy/oauth2-proxy/pkg/apis/options"
 	"github.com/oauth2-proxy/oauth2-proxy/pkg/logger"
 	"github.com/oauth2-proxy/oauth2-proxy/pkg/util"
+	"golang.org/x/net/publicsuffix"
 )
 
 // MakeCookie constructs a cookie from the given parameters,
-// discovering the domain from the request if not specified.
-func MakeCookie(req *http.Request, name string, value string, path string, domain string, httpOnly bool, secure bool, expiration time.Duration, now time.Time, sameSite http.SameSite) *http.Cookie {
-	if domain != "" {
+// discovering the domain from the request if not specified. When hostOnly is
+// true, domain is forced to "" regardless of what was passed in, so the
+// browser scopes the cookie strictly to the request host and won't share it
+// with subdomains.
+func MakeCookie(req *http.Request, name string, value string, path string, domain string, hostOnly bool, httpOnly bool, secure bool, expiration time.Duration, now time.Time, sameSite http.SameSite, disableUASniffing bool) *http.Cookie {
+	if hostOnly {
+		domain = ""
+	} else if domain != "" {
 		host := util.GetRequestHost(req)
 		if h, _, err := net.SplitHostPort(host); err == nil {
 			host = h
@@ -26,8 +31,16 @@ func MakeCookie(req *http.Request, name string, value string, path string, domai
 		}
 	}
 
-	// Adapt the cookie in case of "SameSite=None" Apple issue
-	sameSite = AdaptSameSiteIfAppleIssue(req, sameSite)
+	if sameSite == SameSiteDisabledMode {
+		// The operator explicitly asked for the attribute to be omitted, so
+		// skip the Apple UA workaround entirely and fall back to Go's zero
+		// value, which Cookie.String() never writes to the wire.
+		sameSite = http.SameSiteDefaultMode
+	} else {
+		// Adapt the cookie in case the User-Agent is affected by a known
+		// SameSite quirk (see samesite_quirks.go).
+		sameSite = AdaptSameSiteForUserAgent(req, sameSite, disableUASniffing)
+	}
 
 	return &http.Cookie{
 		Name:     name,
@@ -47,22 +60,63 @@ func MakeCookieFromOptions(req *http.Request, name string, value string, cookieO
 	domain := GetCookieDomain(req, cookieOpts.Domains)
 
 	if domain != "" {
-		return MakeCookie(req, name, value, cookieOpts.Path, domain, cookieOpts.HTTPOnly, cookieOpts.Secure, expiration, now, ParseSameSite(cookieOpts.SameSite))
+		return MakeCookie(req, name, value, cookieOpts.Path, domain, cookieOpts.HostOnly, cookieOpts.HTTPOnly, cookieOpts.Secure, expiration, now, ParseSameSite(cookieOpts.SameSite), cookieOpts.DisableSameSiteQuirks)
 	}
-	// If nothing matches, create the cookie with the shortest domain
+	// If nothing matches, fall back to the last configured domain that
+	// isn't itself a public suffix (same rejection GetCookieDomain applies
+	// to actual matches), or a host-only cookie if every candidate is.
 	defaultDomain := ""
 	if len(cookieOpts.Domains) > 0 {
 		logger.Errorf("Warning: request host %q did not match any of the specific cookie domains of %q", util.GetRequestHost(req), strings.Join(cookieOpts.Domains, ","))
-		defaultDomain = cookieOpts.Domains[len(cookieOpts.Domains)-1]
+		for i := len(cookieOpts.Domains) - 1; i >= 0; i-- {
+			candidate := strings.ToLower(cookieOpts.Domains[i])
+			if suffix, icann := publicsuffix.PublicSuffix(candidate); icann && suffix == candidate {
+				continue
+			}
+			defaultDomain = candidate
+			break
+		}
+	}
+	return MakeCookie(req, name, value, cookieOpts.Path, defaultDomain, cookieOpts.HostOnly, cookieOpts.HTTPOnly, cookieOpts.Secure, expiration, now, ParseSameSite(cookieOpts.SameSite), cookieOpts.DisableSameSiteQuirks)
+}
+
+// WriteCookie writes c via http.SetCookie and, when partitioned is true,
+// appends the CHIPS "Partitioned" attribute to the Set-Cookie header Go just
+// wrote. http.Cookie has no native field for it in the Go versions this
+// proxy supports, so it must be bolted on by rewriting the header rather
+// than set on the struct. Partitioned only has meaning on a
+// "SameSite=None; Secure" cookie, so it is a no-op (with a logged warning)
+// otherwise.
+func WriteCookie(w http.ResponseWriter, c *http.Cookie, partitioned bool) {
+	http.SetCookie(w, c)
+	if !partitioned {
+		return
+	}
+	if c.SameSite != http.SameSiteNoneMode || !c.Secure {
+		logger.Errorf("Warning: cookie %q requested Partitioned but is not SameSite=None; Secure, skipping attribute", c.Name)
+		return
+	}
+	values := w.Header()["Set-Cookie"]
+	if len(values) == 0 {
+		return
 	}
-	return MakeCookie(req, name, value, cookieOpts.Path, defaultDomain, cookieOpts.HTTPOnly, cookieOpts.Secure, expiration, now, ParseSameSite(cookieOpts.SameSite))
+	values[len(values)-1] += "; Partitioned"
 }
 
 // GetCookieDomain returns the correct cookie domain given a list of domains
-// by checking the X-Fowarded-Host and host header of an an http request
+// by checking the X-Fowarded-Host and host header of an an http request.
+// Candidates that are themselves an ICANN public suffix (e.g. "co.uk") are
+// rejected, since a cookie scoped to one would be silently dropped by the
+// browser anyway, and the host comparison is case-insensitive to match
+// HTTP's case-insensitive host semantics.
 func GetCookieDomain(req *http.Request, cookieDomains []string) string {
-	host := util.GetRequestHost(req)
+	host := strings.ToLower(util.GetRequestHost(req))
 	for _, domain := range cookieDomains {
+		domain = strings.ToLower(domain)
+		if suffix, icann := publicsuffix.PublicSuffix(domain); icann && suffix == domain {
+			logger.Errorf("Warning: configured cookie domain %q is a public suffix, refusing to use it", domain)
+			continue
+		}
 		if strings.HasSuffix(host, domain) {
 			return domain
 		}
@@ -70,6 +124,13 @@ func GetCookieDomain(req *http.Request, cookieDomains []string) string {
 	return ""
 }
 
+// SameSiteDisabledMode is a sentinel http.SameSite value, distinct from the
+// zero-value http.SameSiteDefaultMode, used to mark that the SameSite
+// attribute must be omitted from the cookie entirely. Unlike "none", which
+// must always emit "SameSite=None", "disabled" lets operators opt legacy
+// clients (old Safari/iOS) out of the attribute altogether.
+const SameSiteDisabledMode http.SameSite = -1
+
 // Parse a valid http.SameSite value from a user supplied string for use of making cookies.
 func ParseSameSite(v string) http.SameSite {
 	switch v {
@@ -79,37 +140,11 @@ func ParseSameSite(v string) http.SameSite {
 		return http.SameSiteStrictMode
 	case "none":
 		return http.SameSiteNoneMode
+	case "disabled":
+		return SameSiteDisabledMode
 	case "":
 		return http.SameSiteDefaultMode
 	default:
 		panic(fmt.Sprintf("Invalid value for SameSite: %s", v))
 	}
 }
-
-// AdaptSameSiteIfAppleIssue adapts the SameSite property in case of the "SameSite=None" Apple issue
-// by checking the User-Agent of an http request
-// Ref: https://github.com/oauth2-proxy/oauth2-proxy/issues/830
-func AdaptSameSiteIfAppleIssue(req *http.Request, sameSite http.SameSite) http.SameSite {
-	if sameSite == http.SameSiteNoneMode {
-		userAgent := uasurfer.Parse(req.UserAgent())
-
-		// Versions from which this issue has been solved
-		macOSXVersionFixingIssue := uasurfer.Version{
-			Major: 10,
-			Minor: 13,
-			Patch: 0,
-		}
-		iOSVersionFixingIssue := uasurfer.Version{
-			Major: 14,
-			Minor: 0,
-			Patch: 0,
-		}
-
-		// If the user agent is concerned by the issue, provide "SameSite=Lax" instead of "None" to allow some CORS requests within the same domain
-		if (userAgent.OS.Name == uasurfer.OSMacOSX && userAgent.OS.Version.Less(macOSXVersionFixingIssue)) || (userAgent.OS.Name == uasurfer.OSiOS && userAgent.OS.Version.Less(iOSVersionFixingIssue)) {
-			sameSite = http.SameSiteLaxMode
-		}
-	}
-
-	return sameSite
-}
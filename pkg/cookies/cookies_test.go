@@ -0,0 +1,159 @@
+package cookies
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oauth2-proxy/oauth2-proxy/pkg/apis/options"
+)
+
+func TestParseSameSite(t *testing.T) {
+	tests := []struct {
+		in   string
+		want http.SameSite
+	}{
+		{"lax", http.SameSiteLaxMode},
+		{"strict", http.SameSiteStrictMode},
+		{"none", http.SameSiteNoneMode},
+		{"disabled", SameSiteDisabledMode},
+		{"", http.SameSiteDefaultMode},
+	}
+	for _, tt := range tests {
+		if got := ParseSameSite(tt.in); got != tt.want {
+			t.Errorf("ParseSameSite(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseSameSiteInvalidPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected ParseSameSite to panic on an invalid value")
+		}
+	}()
+	ParseSameSite("bogus")
+}
+
+func TestMakeCookieSameSiteDisabledOmitsAttribute(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	now := time.Unix(0, 0)
+
+	c := MakeCookie(req, "_oauth2_proxy", "value", "/", "", false, true, true, time.Hour, now, SameSiteDisabledMode, false)
+
+	if strings.Contains(c.String(), "SameSite") {
+		t.Errorf("expected Set-Cookie header to omit SameSite entirely, got %q", c.String())
+	}
+}
+
+func TestMakeCookieSameSiteNoneStillAppliesQuirks(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_12_6) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.1.1 Safari/605.1.15")
+	now := time.Unix(0, 0)
+
+	c := MakeCookie(req, "_oauth2_proxy", "value", "/", "", false, true, true, time.Hour, now, http.SameSiteNoneMode, false)
+
+	if c.SameSite != http.SameSiteLaxMode {
+		t.Errorf("expected the Apple SameSite quirk to downgrade to Lax, got %v", c.SameSite)
+	}
+}
+
+func TestMakeCookieHostOnlyForcesEmptyDomain(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	now := time.Unix(0, 0)
+
+	c := MakeCookie(req, "_oauth2_proxy", "value", "/", "example.com", true, true, true, time.Hour, now, http.SameSiteLaxMode, false)
+
+	if c.Domain != "" {
+		t.Errorf("expected HostOnly to force an empty Domain, got %q", c.Domain)
+	}
+}
+
+func TestMakeCookieFromOptionsHostOnly(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "app.example.com"
+	cookieOpts := &options.Cookie{Path: "/", Domains: []string{"example.com"}, HostOnly: true}
+
+	c := MakeCookieFromOptions(req, "_oauth2_proxy", "value", cookieOpts, time.Hour, time.Unix(0, 0))
+
+	if c.Domain != "" {
+		t.Errorf("expected HostOnly to override the configured Domains, got %q", c.Domain)
+	}
+}
+
+func TestWriteCookiePartitionedAppendsAttribute(t *testing.T) {
+	rec := httptest.NewRecorder()
+	c := &http.Cookie{Name: "_oauth2_proxy", Value: "v", SameSite: http.SameSiteNoneMode, Secure: true}
+
+	WriteCookie(rec, c, true)
+
+	header := rec.Result().Header.Get("Set-Cookie")
+	if !strings.Contains(header, "; Partitioned") {
+		t.Errorf("expected Set-Cookie header to carry Partitioned, got %q", header)
+	}
+}
+
+func TestWriteCookiePartitionedSkippedWithoutSameSiteNoneSecure(t *testing.T) {
+	rec := httptest.NewRecorder()
+	c := &http.Cookie{Name: "_oauth2_proxy", Value: "v", SameSite: http.SameSiteLaxMode, Secure: true}
+
+	WriteCookie(rec, c, true)
+
+	header := rec.Result().Header.Get("Set-Cookie")
+	if strings.Contains(header, "Partitioned") {
+		t.Errorf("expected Partitioned to be skipped for a non SameSite=None;Secure cookie, got %q", header)
+	}
+}
+
+func TestGetCookieDomainMatchesConfiguredDomain(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "app.example.com"
+
+	if got := GetCookieDomain(req, []string{"example.com"}); got != "example.com" {
+		t.Errorf("GetCookieDomain = %q, want %q", got, "example.com")
+	}
+}
+
+func TestGetCookieDomainRejectsPublicSuffix(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "app.co.uk"
+
+	if got := GetCookieDomain(req, []string{"co.uk"}); got != "" {
+		t.Errorf("GetCookieDomain = %q, want \"\" for a public suffix domain", got)
+	}
+}
+
+func TestGetCookieDomainNoMatch(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "app.other.com"
+
+	if got := GetCookieDomain(req, []string{"example.com"}); got != "" {
+		t.Errorf("GetCookieDomain = %q, want \"\" when no configured domain matches the host", got)
+	}
+}
+
+func TestMakeCookieFromOptionsFallbackRejectsPublicSuffix(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "app.other.com"
+	cookieOpts := &options.Cookie{Path: "/", Domains: []string{"co.uk"}}
+
+	c := MakeCookieFromOptions(req, "_oauth2_proxy", "value", cookieOpts, time.Hour, time.Unix(0, 0))
+
+	if c.Domain != "" {
+		t.Errorf("Domain = %q, want \"\" (host-only) when the only fallback candidate is a public suffix", c.Domain)
+	}
+}
+
+func TestMakeCookieFromOptionsFallbackSkipsPublicSuffixCandidate(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "app.other.com"
+	cookieOpts := &options.Cookie{Path: "/", Domains: []string{"example.com", "co.uk"}}
+
+	c := MakeCookieFromOptions(req, "_oauth2_proxy", "value", cookieOpts, time.Hour, time.Unix(0, 0))
+
+	if c.Domain != "example.com" {
+		t.Errorf("Domain = %q, want the non-public-suffix fallback %q", c.Domain, "example.com")
+	}
+}
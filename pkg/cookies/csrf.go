@@ -0,0 +1,116 @@
+package cookies
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oauth2-proxy/oauth2-proxy/pkg/apis/options"
+)
+
+// MakeCSRFCookie issues an HMAC-signed CSRF cookie that embeds its own
+// expiry (now+expiration), bound to a caller-supplied nonce, and returns the
+// cookie alongside the signed token. Callers following the "double-submit"
+// pattern embed that same token in the OAuth state/PKCE parameter and
+// confirm it round-trips via VerifyCSRFCookie. nonce must be a
+// caller-generated random value: with a constant nonce the token for a given
+// secret differs only by its embedded expiry, so it is predictable and
+// defeats the protection entirely; MakeCSRFCookie rejects an empty one. httpOnly is taken as an
+// explicit parameter, rather than from cookieOpts, so JS-driven flows can
+// request a readable cookie without weakening every other cookie the proxy
+// sets.
+func MakeCSRFCookie(req *http.Request, name string, secret []byte, cookieOpts *options.Cookie, httpOnly bool, nonce string, expiration time.Duration, now time.Time) (*http.Cookie, string, error) {
+	if nonce == "" {
+		return nil, "", fmt.Errorf("CSRF nonce must not be empty")
+	}
+
+	token, err := signCSRFToken(secret, nonce, now.Add(expiration))
+	if err != nil {
+		return nil, "", err
+	}
+
+	cookie := MakeCookieFromOptions(req, name, token, cookieOpts, expiration, now)
+	cookie.HttpOnly = httpOnly
+	return cookie, token, nil
+}
+
+// VerifyCSRFCookie reads the named CSRF cookie, checks its HMAC signature in
+// constant time, confirms it matches the nonce that was embedded alongside
+// it (e.g. in the OAuth state parameter), and confirms the expiry embedded
+// in the token has not passed as of now. The embedded expiry is what
+// actually bounds the token's lifetime: the cookie's Expires attribute is
+// enforced by the browser only, so a captured token replayed directly
+// against the server would otherwise be accepted forever.
+func VerifyCSRFCookie(req *http.Request, name string, secret []byte, nonce string, now time.Time) (bool, error) {
+	if nonce == "" {
+		return false, fmt.Errorf("CSRF nonce must not be empty")
+	}
+
+	c, err := req.Cookie(name)
+	if err != nil {
+		return false, err
+	}
+	return verifyCSRFToken(secret, nonce, c.Value, now)
+}
+
+// signCSRFToken produces a "<nonce>.<expiresAt>.<signature>" token: nonce
+// base64url-encoded, expiresAt a Unix timestamp, both covered by an
+// HMAC-SHA256 signature keyed on secret so neither can be tampered with
+// independently of the other.
+func signCSRFToken(secret []byte, nonce string, expiresAt time.Time) (string, error) {
+	encodedNonce := base64.RawURLEncoding.EncodeToString([]byte(nonce))
+	expiresAtStr := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	mac := hmac.New(sha256.New, secret)
+	if _, err := mac.Write([]byte(encodedNonce + "." + expiresAtStr)); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s.%s.%s", encodedNonce, expiresAtStr, base64.RawURLEncoding.EncodeToString(mac.Sum(nil))), nil
+}
+
+// verifyCSRFToken re-derives the signature for the nonce and expiry embedded
+// in token and compares it in constant time, then requires the embedded
+// nonce to match expectedNonce and the embedded expiry to still be in the
+// future relative to now.
+func verifyCSRFToken(secret []byte, expectedNonce string, token string, now time.Time) (bool, error) {
+	parts := strings.SplitN(token, ".", 3)
+	if len(parts) != 3 {
+		return false, fmt.Errorf("invalid CSRF token format")
+	}
+	encodedNonce, expiresAtStr, encodedSig := parts[0], parts[1], parts[2]
+
+	nonce, err := base64.RawURLEncoding.DecodeString(encodedNonce)
+	if err != nil {
+		return false, fmt.Errorf("invalid CSRF token nonce: %v", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil {
+		return false, fmt.Errorf("invalid CSRF token signature: %v", err)
+	}
+	expiresAtUnix, err := strconv.ParseInt(expiresAtStr, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid CSRF token expiry: %v", err)
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	if _, err := mac.Write([]byte(encodedNonce + "." + expiresAtStr)); err != nil {
+		return false, err
+	}
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return false, nil
+	}
+
+	if string(nonce) != expectedNonce {
+		return false, nil
+	}
+	if !now.Before(time.Unix(expiresAtUnix, 0)) {
+		return false, nil
+	}
+	return true, nil
+}
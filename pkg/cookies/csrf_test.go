@@ -0,0 +1,131 @@
+package cookies
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/oauth2-proxy/oauth2-proxy/pkg/apis/options"
+)
+
+var csrfTestSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func TestMakeVerifyCSRFCookieRoundTrip(t *testing.T) {
+	cookieOpts := &options.Cookie{Path: "/"}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	now := time.Unix(1000, 0)
+
+	cookie, token, err := MakeCSRFCookie(req, "_csrf", csrfTestSecret, cookieOpts, true, "nonce-value", time.Minute, now)
+	if err != nil {
+		t.Fatalf("MakeCSRFCookie returned error: %v", err)
+	}
+	if cookie.Value != token {
+		t.Fatalf("cookie value %q does not match returned token %q", cookie.Value, token)
+	}
+
+	verifyReq := httptest.NewRequest(http.MethodGet, "/", nil)
+	verifyReq.AddCookie(cookie)
+
+	ok, err := VerifyCSRFCookie(verifyReq, "_csrf", csrfTestSecret, "nonce-value", now.Add(30*time.Second))
+	if err != nil {
+		t.Fatalf("VerifyCSRFCookie returned error: %v", err)
+	}
+	if !ok {
+		t.Error("expected a freshly issued CSRF cookie with the right nonce to verify")
+	}
+}
+
+func TestVerifyCSRFCookieRejectsExpiredToken(t *testing.T) {
+	cookieOpts := &options.Cookie{Path: "/"}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	now := time.Unix(1000, 0)
+
+	cookie, _, err := MakeCSRFCookie(req, "_csrf", csrfTestSecret, cookieOpts, true, "nonce-value", time.Minute, now)
+	if err != nil {
+		t.Fatalf("MakeCSRFCookie returned error: %v", err)
+	}
+
+	verifyReq := httptest.NewRequest(http.MethodGet, "/", nil)
+	verifyReq.AddCookie(cookie)
+
+	ok, err := VerifyCSRFCookie(verifyReq, "_csrf", csrfTestSecret, "nonce-value", now.Add(2*time.Minute))
+	if err != nil {
+		t.Fatalf("VerifyCSRFCookie returned error: %v", err)
+	}
+	if ok {
+		t.Error("expected a token replayed after its embedded expiry to be rejected")
+	}
+}
+
+func TestVerifyCSRFCookieRejectsWrongNonce(t *testing.T) {
+	cookieOpts := &options.Cookie{Path: "/"}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	now := time.Unix(1000, 0)
+
+	cookie, _, err := MakeCSRFCookie(req, "_csrf", csrfTestSecret, cookieOpts, true, "nonce-value", time.Minute, now)
+	if err != nil {
+		t.Fatalf("MakeCSRFCookie returned error: %v", err)
+	}
+
+	verifyReq := httptest.NewRequest(http.MethodGet, "/", nil)
+	verifyReq.AddCookie(cookie)
+
+	ok, err := VerifyCSRFCookie(verifyReq, "_csrf", csrfTestSecret, "some-other-nonce", now)
+	if err != nil {
+		t.Fatalf("VerifyCSRFCookie returned error: %v", err)
+	}
+	if ok {
+		t.Error("expected a token with a mismatched nonce to be rejected")
+	}
+}
+
+func TestVerifyCSRFCookieRejectsTamperedSignature(t *testing.T) {
+	cookieOpts := &options.Cookie{Path: "/"}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	now := time.Unix(1000, 0)
+
+	cookie, _, err := MakeCSRFCookie(req, "_csrf", csrfTestSecret, cookieOpts, true, "nonce-value", time.Minute, now)
+	if err != nil {
+		t.Fatalf("MakeCSRFCookie returned error: %v", err)
+	}
+	cookie.Value = cookie.Value[:len(cookie.Value)-1] + "x"
+
+	verifyReq := httptest.NewRequest(http.MethodGet, "/", nil)
+	verifyReq.AddCookie(cookie)
+
+	ok, err := VerifyCSRFCookie(verifyReq, "_csrf", csrfTestSecret, "nonce-value", now)
+	if err != nil {
+		t.Fatalf("VerifyCSRFCookie returned error: %v", err)
+	}
+	if ok {
+		t.Error("expected a tampered signature to be rejected")
+	}
+}
+
+func TestMakeCSRFCookieRejectsEmptyNonce(t *testing.T) {
+	cookieOpts := &options.Cookie{Path: "/"}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	if _, _, err := MakeCSRFCookie(req, "_csrf", csrfTestSecret, cookieOpts, true, "", time.Minute, time.Unix(0, 0)); err == nil {
+		t.Error("expected MakeCSRFCookie to reject an empty nonce")
+	}
+}
+
+func TestTwoCSRFTokensForSameNonceAreNotIdentical(t *testing.T) {
+	cookieOpts := &options.Cookie{Path: "/"}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	_, token1, err := MakeCSRFCookie(req, "_csrf", csrfTestSecret, cookieOpts, true, "nonce-value", time.Minute, time.Unix(1000, 0))
+	if err != nil {
+		t.Fatalf("MakeCSRFCookie returned error: %v", err)
+	}
+	_, token2, err := MakeCSRFCookie(req, "_csrf", csrfTestSecret, cookieOpts, true, "nonce-value", time.Minute, time.Unix(2000, 0))
+	if err != nil {
+		t.Fatalf("MakeCSRFCookie returned error: %v", err)
+	}
+
+	if token1 == token2 {
+		t.Error("expected tokens issued at different times to differ, since they embed their own expiry")
+	}
+}
@@ -0,0 +1,105 @@
+package cookies
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/avct/uasurfer"
+)
+
+// SameSiteQuirkMatcher reports whether a parsed User-Agent is affected by a
+// known SameSite quirk.
+type SameSiteQuirkMatcher func(ua uasurfer.UserAgent) bool
+
+// SameSiteQuirkAdapter rewrites a SameSite value for a User-Agent matched by
+// the corresponding SameSiteQuirkMatcher.
+type SameSiteQuirkAdapter func(http.SameSite) http.SameSite
+
+// sameSiteQuirk pairs a matcher with the adapter to run when it matches.
+type sameSiteQuirk struct {
+	name    string
+	matches SameSiteQuirkMatcher
+	adapt   SameSiteQuirkAdapter
+}
+
+var (
+	sameSiteQuirksMu sync.RWMutex
+	sameSiteQuirks   []sameSiteQuirk
+)
+
+// RegisterSameSiteQuirk adds a rule to the registry consulted by
+// AdaptSameSiteForUserAgent. Rules are tried in registration order and the
+// first match wins. The built-in rules are registered at package init, so a
+// rule added here is appended after them and is only consulted for
+// User-Agents none of them match. name is used only for diagnostics.
+func RegisterSameSiteQuirk(name string, matches SameSiteQuirkMatcher, adapt SameSiteQuirkAdapter) {
+	sameSiteQuirksMu.Lock()
+	defer sameSiteQuirksMu.Unlock()
+	sameSiteQuirks = append(sameSiteQuirks, sameSiteQuirk{name: name, matches: matches, adapt: adapt})
+}
+
+func init() {
+	RegisterSameSiteQuirk("apple-samesite-none-rejected", isAppleSameSiteNoneIssue, downgradeToLax)
+	RegisterSameSiteQuirk("chrome-samesite-none-as-strict", isChromeSameSiteNoneAsStrictIssue, omitSameSite)
+	RegisterSameSiteQuirk("ucbrowser-samesite-none-rejected", isUCBrowserSameSiteNoneIssue, omitSameSite)
+}
+
+// AdaptSameSiteForUserAgent walks the registered SameSiteQuirks and returns
+// the SameSite value adapted by the first matching rule, or sameSite
+// unchanged if none match or disableUASniffing is set. Only
+// "SameSite=None" cookies are ever affected, since that's the only value
+// these quirks are about working around.
+// Ref: https://github.com/oauth2-proxy/oauth2-proxy/issues/830
+func AdaptSameSiteForUserAgent(req *http.Request, sameSite http.SameSite, disableUASniffing bool) http.SameSite {
+	if disableUASniffing || sameSite != http.SameSiteNoneMode {
+		return sameSite
+	}
+
+	userAgent := uasurfer.Parse(req.UserAgent())
+
+	sameSiteQuirksMu.RLock()
+	defer sameSiteQuirksMu.RUnlock()
+	for _, quirk := range sameSiteQuirks {
+		if quirk.matches(*userAgent) {
+			return quirk.adapt(sameSite)
+		}
+	}
+	return sameSite
+}
+
+func downgradeToLax(http.SameSite) http.SameSite {
+	return http.SameSiteLaxMode
+}
+
+// omitSameSite drops the attribute rather than downgrading it, for browsers
+// that reject the cookie outright if they see a SameSite value they don't
+// recognize or mishandle.
+func omitSameSite(http.SameSite) http.SameSite {
+	return http.SameSiteDefaultMode
+}
+
+// isAppleSameSiteNoneIssue matches macOS < 10.13 and iOS < 14, which treat a
+// "SameSite=None" cookie as "SameSite=Strict" instead of honouring it.
+func isAppleSameSiteNoneIssue(ua uasurfer.UserAgent) bool {
+	macOSVersionFixingIssue := uasurfer.Version{Major: 10, Minor: 13, Patch: 0}
+	iOSVersionFixingIssue := uasurfer.Version{Major: 14, Minor: 0, Patch: 0}
+
+	return (ua.OS.Name == uasurfer.OSMacOSX && ua.OS.Version.Less(macOSVersionFixingIssue)) ||
+		(ua.OS.Name == uasurfer.OSiOS && ua.OS.Version.Less(iOSVersionFixingIssue))
+}
+
+// isChromeSameSiteNoneAsStrictIssue matches Chrome 51 through 66, which
+// treated "SameSite=None" as "SameSite=Strict" rather than "no restriction".
+func isChromeSameSiteNoneAsStrictIssue(ua uasurfer.UserAgent) bool {
+	return ua.Browser.Name == uasurfer.BrowserChrome && ua.Browser.Version.Major >= 51 && ua.Browser.Version.Major <= 66
+}
+
+// isUCBrowserSameSiteNoneIssue matches UC Browser releases before 12.13,
+// which reject cookies carrying a "SameSite=None" attribute outright.
+func isUCBrowserSameSiteNoneIssue(ua uasurfer.UserAgent) bool {
+	if ua.Browser.Name != uasurfer.BrowserUCBrowser {
+		return false
+	}
+	fixedVersion := uasurfer.Version{Major: 12, Minor: 13, Patch: 0}
+	return ua.Browser.Version.Less(fixedVersion)
+}
@@ -0,0 +1,32 @@
+package cookies
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// BenchmarkAdaptSameSiteForUserAgent measures the cost of running the
+// SameSite quirks registry, since uasurfer.Parse runs on every MakeCookie
+// call for a SameSite=None cookie and is a measurable hot path under load.
+func BenchmarkAdaptSameSiteForUserAgent(b *testing.B) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_12_6) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.1.1 Safari/605.1.15")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		AdaptSameSiteForUserAgent(req, http.SameSiteNoneMode, false)
+	}
+}
+
+// BenchmarkAdaptSameSiteForUserAgent_Disabled measures the cost with UA
+// sniffing turned off, which should be close to a no-op.
+func BenchmarkAdaptSameSiteForUserAgent_Disabled(b *testing.B) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_12_6) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.1.1 Safari/605.1.15")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		AdaptSameSiteForUserAgent(req, http.SameSiteNoneMode, true)
+	}
+}
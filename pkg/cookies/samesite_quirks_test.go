@@ -0,0 +1,74 @@
+package cookies
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/avct/uasurfer"
+	"github.com/oauth2-proxy/oauth2-proxy/pkg/apis/options"
+)
+
+func TestAdaptSameSiteForUserAgentAppleQuirkDowngradesToLax(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_12_6) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.1.1 Safari/605.1.15")
+
+	if got := AdaptSameSiteForUserAgent(req, http.SameSiteNoneMode, false); got != http.SameSiteLaxMode {
+		t.Errorf("AdaptSameSiteForUserAgent = %v, want SameSiteLaxMode", got)
+	}
+}
+
+func TestAdaptSameSiteForUserAgentUnaffectedBrowserUnchanged(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/115.0.0.0 Safari/537.36")
+
+	if got := AdaptSameSiteForUserAgent(req, http.SameSiteNoneMode, false); got != http.SameSiteNoneMode {
+		t.Errorf("AdaptSameSiteForUserAgent = %v, want unchanged SameSiteNoneMode", got)
+	}
+}
+
+func TestAdaptSameSiteForUserAgentIgnoresNonNoneValues(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_12_6) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.1.1 Safari/605.1.15")
+
+	if got := AdaptSameSiteForUserAgent(req, http.SameSiteLaxMode, false); got != http.SameSiteLaxMode {
+		t.Errorf("AdaptSameSiteForUserAgent = %v, want unchanged SameSiteLaxMode", got)
+	}
+}
+
+func TestAdaptSameSiteForUserAgentDisableUASniffing(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_12_6) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.1.1 Safari/605.1.15")
+
+	if got := AdaptSameSiteForUserAgent(req, http.SameSiteNoneMode, true); got != http.SameSiteNoneMode {
+		t.Errorf("AdaptSameSiteForUserAgent = %v, want unchanged SameSiteNoneMode when UA sniffing is disabled", got)
+	}
+}
+
+func TestMakeCookieFromOptionsDisableSameSiteQuirks(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_12_6) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.1.1 Safari/605.1.15")
+	cookieOpts := &options.Cookie{Path: "/", SameSite: "none", DisableSameSiteQuirks: true}
+
+	c := MakeCookieFromOptions(req, "_oauth2_proxy", "value", cookieOpts, time.Hour, time.Unix(0, 0))
+
+	if c.SameSite != http.SameSiteNoneMode {
+		t.Errorf("SameSite = %v, want unchanged SameSiteNoneMode with DisableSameSiteQuirks set", c.SameSite)
+	}
+}
+
+func TestRegisterSameSiteQuirkCustomRuleApplies(t *testing.T) {
+	savedQuirks := sameSiteQuirks
+	sameSiteQuirks = nil
+	defer func() { sameSiteQuirks = savedQuirks }()
+
+	RegisterSameSiteQuirk("always-omit", func(uasurfer.UserAgent) bool { return true }, omitSameSite)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "anything")
+
+	if got := AdaptSameSiteForUserAgent(req, http.SameSiteNoneMode, false); got != http.SameSiteDefaultMode {
+		t.Errorf("AdaptSameSiteForUserAgent = %v, want SameSiteDefaultMode from the custom rule", got)
+	}
+}